@@ -3,13 +3,13 @@ package isolation
 import (
   "io"
   "fmt"
-  "errors"
+  "regexp"
 
   clientset "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset"
 
   "k8s.io/kubernetes/pkg/admission"
   "k8s.io/kubernetes/pkg/api"
-  k8sError "k8s.io/kubernetes/pkg/api/errors"
+  "k8s.io/kubernetes/pkg/client/cache"
   "k8s.io/kubernetes/pkg/controller/framework"
 	"k8s.io/kubernetes/pkg/controller/framework/informers"
 )
@@ -17,15 +17,20 @@ import (
 const (
   // IngressAnnotationKey key for the network policy annotation in a namespace
   IngressAnnotationKey = "net.beta.kubernetes.io/network-policy"
-  // IngressAnnotationValue is the policy that belongs to the NetworkPolicy key
-  IngressAnnotationValue = "{\"ingress\": {\"isolation\": \"DefaultDeny\"}}"
   // NameLabelKey key for the name label used in network policy verification
   NameLabelKey = "Name"
+  // MetadataNameLabelKey is the well-known label downstream NetworkPolicyPeer
+  // namespaceSelector rules match on to reference this namespace by name.
+  MetadataNameLabelKey = "kubernetes.io/metadata.name"
 )
 
 func init() {
   admission.RegisterPlugin("NamespaceNetworkIsolation", func(client clientset.Interface, config io.Reader) (admission.Interface, error) {
-    return NewIsolation(client), nil
+    parsed, err := LoadConfiguration(config)
+    if err != nil {
+      return nil, err
+    }
+    return NewIsolation(client, parsed), nil
   })
 }
 
@@ -33,61 +38,88 @@ type isolation struct {
   *admission.Handler
   client             clientset.Interface
   namespaceInformer framework.SharedIndexInformer
+  config             *Configuration
+  reconciler         *reconciler
+  allowList          []*regexp.Regexp
+  denyList           []*regexp.Regexp
+  stopCh             chan struct{}
 }
 
 var _ = admission.WantsInformerFactory(&isolation{})
 
 func (i *isolation) Admit(a admission.Attributes) (err error) {
   // only looking for *new namespaces* to edit
-  if a.GetKind().GroupKind() != api.Kind("Namespace") || a.GetOperation() != admission.Create || a.GetOperation() != admission.Update {
+  if a.GetKind().GroupKind() != api.Kind("Namespace") || (a.GetOperation() != admission.Create && a.GetOperation() != admission.Update) {
     return nil
   }
 
   namespaceObj := a.GetObject().(*api.Namespace)
-  addIngressAnnotation(namespaceObj)
-  addNameLabel(namespaceObj)
 
-  i.namespaceInformer.GetStore().Update(namespaceObj)
-
-  // verify that our update worked
-  obj, _, err := i.namespaceInformer.GetStore().Get(namespaceObj)
-  if err != nil {
-    return k8sError.NewInternalError(err)
+  rule, exempt := resolveRule(i.config, namespaceObj)
+  if exempt || rule == nil {
+    // either an exempt-labeled namespace, or no configured rule matched it
+    return nil
   }
 
-  checkObj := obj.(*api.Namespace)
-  checkAnnotations := checkObj.GetAnnotations()
-  if checkAnnotations == nil { // there are no annotations in updated object
-    return k8sError.NewInternalError(errors.New("Failed to add ingress isolation annotation"))
-  } else if val, exists := checkAnnotations[IngressAnnotationKey]; !exists ||
-    val != IngressAnnotationValue { // we didn't save the updated annotation properly
-    return k8sError.NewInternalError(errors.New("Failed to update ingress isolation annotation properly"))
+  if a.GetOperation() == admission.Create {
+    // mutate the object admission is processing in place; the apiserver persists
+    // this through the normal create pipeline, so there's nothing further to do.
+    i.mergeIngressAnnotation(namespaceObj, rule)
+    addNameLabel(namespaceObj)
+    return nil
   }
 
-  // annotation added
+  // On Update, namespaceObj already reflects whatever the caller sent, which may not
+  // go on to be persisted with our annotation (e.g. a strategic-merge update that
+  // only touches other fields). Admission must not block on API calls, so rather
+  // than patching inline here, leave convergence to i.reconciler: it observes this
+  // same Update through namespaceInformer once it lands and patches asynchronously
+  // if the annotation is still missing.
   return nil
 }
 
-// NewIsolation ensures that a newly created namespace is annotated with complete network isolation
-func NewIsolation(c clientset.Interface) admission.Interface {
+// NewIsolation ensures that a newly created namespace is annotated with network
+// isolation according to config. A nil config falls back to the plugin's historical
+// behavior of annotating every namespace DefaultDeny on ingress.
+func NewIsolation(c clientset.Interface, config *Configuration) admission.Interface {
+  if config == nil {
+    config = defaultConfiguration()
+  }
   return &isolation{
-    client:  c,
-    Handler: admission.NewHandler(admission.Create, admission.Update, admission.Delete),
+    client:    c,
+    Handler:   admission.NewHandler(admission.Create, admission.Update, admission.Delete),
+    config:    config,
+    allowList: compileOverridePatterns(config.AnnotationAllowList),
+    denyList:  compileOverridePatterns(config.AnnotationDenyList),
+    stopCh:    make(chan struct{}),
   }
 }
 
-func addIngressAnnotation(namespaceObj *api.Namespace) {
+// annotationValue renders rule as the legacy net.beta.kubernetes.io/network-policy
+// annotation value, a structured JSON object carrying the ingress isolation mode
+// and, when the rule configures one, an egress isolation mode alongside it.
+func annotationValue(rule *Rule) string {
+  ingress := normalizedIngress(rule)
+
+  if rule.Egress == "" {
+    return fmt.Sprintf("{\"ingress\": {\"isolation\": %q}}", ingress)
+  }
+  return fmt.Sprintf("{\"ingress\": {\"isolation\": %q}, \"egress\": {\"isolation\": %q}}", ingress, rule.Egress)
+}
+
+func addIngressAnnotation(namespaceObj *api.Namespace, rule *Rule) {
+  value := annotationValue(rule)
   annotations := namespaceObj.GetAnnotations()
 
   if annotations == nil { // doesn't have any annotations, initialize them
     annotations = map[string]string{}
   } else if val, exists := annotations[IngressAnnotationKey]; exists &&
-    val == IngressAnnotationValue { // has the ingress annotation & its what we want, we're done
+    val == value { // has the ingress annotation & its what we want, we're done
     return
   }
 
   // doesn't have the annotation, so we add it
-  annotations[IngressAnnotationKey] = IngressAnnotationValue
+  annotations[IngressAnnotationKey] = value
   namespaceObj.SetAnnotations(annotations)
 }
 
@@ -95,18 +127,50 @@ func addNameLabel(namespaceObj *api.Namespace) {
   labels := namespaceObj.GetLabels()
   if labels == nil { // no labels
     labels = map[string]string{}
-  } else if _, exists := labels[NameLabelKey]; exists {
-    return // label already exists
   }
 
-  // add `name` label with this namespace's name
-  labels[NameLabelKey] = namespaceObj.Name
+  if _, exists := labels[NameLabelKey]; !exists {
+    labels[NameLabelKey] = namespaceObj.Name
+  }
+
+  // kubernetes.io/metadata.name lets NetworkPolicyPeer.namespaceSelector rules
+  // (e.g. matchLabels: {kubernetes.io/metadata.name: foo}) resolve this namespace
+  // as a cross-namespace peer without any extra operator configuration.
+  if _, exists := labels[MetadataNameLabelKey]; !exists {
+    labels[MetadataNameLabelKey] = namespaceObj.Name
+  }
+
   namespaceObj.SetLabels(labels)
 }
 
 func (i *isolation) SetInformerFactory(f informers.SharedInformerFactory) {
 	i.namespaceInformer = f.Namespaces().Informer()
 	i.SetReadyFunc(i.namespaceInformer.HasSynced)
+
+	// Admit only ever mutates the object already flowing through the normal
+	// create/update pipeline, so it proves nothing about what actually lands in
+	// etcd. i.reconciler watches the same namespaceInformer and independently
+	// verifies the annotation, labels and NetworkPolicy, re-applying anything
+	// missing or drifted — this is the event-driven reconciliation the admission
+	// path itself must not block on.
+	i.reconciler = newReconciler(i.client, i.namespaceInformer, i.config, i.allowList, i.denyList)
+	go i.startReconciler()
+}
+
+// startReconciler blocks until the namespaceInformer has synced, then runs the
+// reconciler's workers until i.stopCh is closed.
+func (i *isolation) startReconciler() {
+	if !cache.WaitForCacheSync(i.stopCh, i.namespaceInformer.HasSynced) {
+		return
+	}
+	i.reconciler.Run(2, i.stopCh)
+}
+
+// Stop shuts down the background reconciler. It is not invoked by the admission
+// framework itself; callers that own this plugin's lifecycle (e.g. tests) should
+// call it to release the reconciler's goroutines.
+func (i *isolation) Stop() {
+	close(i.stopCh)
 }
 
 func (i *isolation) Validate() error {
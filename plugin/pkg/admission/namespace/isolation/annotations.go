@@ -0,0 +1,79 @@
+package isolation
+
+import (
+  "regexp"
+
+  "k8s.io/kubernetes/pkg/api"
+)
+
+// compileOverridePatterns compiles the configured allow/deny regex lists, skipping
+// (and ignoring) any pattern that fails to compile rather than failing admission
+// entirely over an operator typo.
+func compileOverridePatterns(patterns []string) []*regexp.Regexp {
+  compiled := make([]*regexp.Regexp, 0, len(patterns))
+  for _, p := range patterns {
+    re, err := regexp.Compile(p)
+    if err != nil {
+      continue
+    }
+    compiled = append(compiled, re)
+  }
+  return compiled
+}
+
+// allowOverride reports whether key may be restored to its user-supplied value
+// after the plugin applies its own default annotation: it must match at least one
+// allowList pattern and none of the denyList patterns.
+func allowOverride(allowList, denyList []*regexp.Regexp, key string) bool {
+  if !matchesAny(allowList, key) {
+    return false
+  }
+  return !matchesAny(denyList, key)
+}
+
+func (i *isolation) allowOverride(key string) bool {
+  return allowOverride(i.allowList, i.denyList, key)
+}
+
+func matchesAny(patterns []*regexp.Regexp, key string) bool {
+  for _, re := range patterns {
+    if re.MatchString(key) {
+      return true
+    }
+  }
+  return false
+}
+
+// cloneAnnotations returns a shallow copy of annotations so callers can capture the
+// namespace's user-supplied state before the plugin mutates it in place.
+func cloneAnnotations(annotations map[string]string) map[string]string {
+  if annotations == nil {
+    return nil
+  }
+  clone := make(map[string]string, len(annotations))
+  for k, v := range annotations {
+    clone[k] = v
+  }
+  return clone
+}
+
+// mergeIngressAnnotation applies the plugin's default isolation annotation and then,
+// for any user-supplied annotation matching the configured allow-list (and not
+// vetoed by the deny-list), restores the operator's original value. This lets an
+// operator override the computed isolation posture on specific namespaces without
+// the plugin silently overwriting it, while never touching unrelated metadata the
+// user set.
+func (i *isolation) mergeIngressAnnotation(namespaceObj *api.Namespace, rule *Rule) {
+  userAnnotations := cloneAnnotations(namespaceObj.GetAnnotations())
+
+  addIngressAnnotation(namespaceObj, rule)
+
+  for key, value := range userAnnotations {
+    if !i.allowOverride(key) {
+      continue
+    }
+    annotations := namespaceObj.GetAnnotations()
+    annotations[key] = value
+    namespaceObj.SetAnnotations(annotations)
+  }
+}
@@ -0,0 +1,33 @@
+package isolation
+
+import "testing"
+
+func TestAllowOverride(t *testing.T) {
+  allowList := compileOverridePatterns([]string{`^net\.beta\.kubernetes\.io/.*`})
+  denyList := compileOverridePatterns([]string{`^net\.beta\.kubernetes\.io/network-policy-locked$`})
+
+  tests := []struct {
+    name string
+    key  string
+    want bool
+  }{
+    {name: "matches allow list", key: "net.beta.kubernetes.io/network-policy", want: true},
+    {name: "matches neither list", key: "example.com/unrelated", want: false},
+    {name: "matches allow list but vetoed by deny list", key: "net.beta.kubernetes.io/network-policy-locked", want: false},
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      if got := allowOverride(allowList, denyList, tt.key); got != tt.want {
+        t.Fatalf("allowOverride(%q) = %v, want %v", tt.key, got, tt.want)
+      }
+    })
+  }
+}
+
+func TestCompileOverridePatternsSkipsInvalidRegex(t *testing.T) {
+  compiled := compileOverridePatterns([]string{`(unterminated`, `^valid$`})
+  if len(compiled) != 1 {
+    t.Fatalf("len(compiled) = %d, want 1 (invalid pattern should be skipped)", len(compiled))
+  }
+}
@@ -0,0 +1,92 @@
+package isolation
+
+import (
+  "fmt"
+  "io"
+
+  "k8s.io/kubernetes/pkg/api/unversioned"
+  "k8s.io/kubernetes/pkg/util/yaml"
+)
+
+// IsolationMode is the network isolation posture applied to a namespace.
+type IsolationMode string
+
+const (
+  // DefaultDeny denies all traffic in the given direction unless a NetworkPolicy allows it.
+  DefaultDeny IsolationMode = "DefaultDeny"
+  // DefaultAllow permits all traffic in the given direction.
+  DefaultAllow IsolationMode = "DefaultAllow"
+)
+
+// Rule describes the isolation posture to apply to namespaces matching NamespaceSelector.
+// Rules are evaluated in order and the first match wins.
+type Rule struct {
+  // NamespaceSelector restricts this rule to namespaces whose labels match. A nil
+  // selector matches every namespace.
+  NamespaceSelector *unversioned.LabelSelector `json:"namespaceSelector,omitempty"`
+  // Ingress is the isolation mode to apply to ingress traffic.
+  Ingress IsolationMode `json:"ingress,omitempty"`
+  // Egress is the isolation mode to apply to egress traffic.
+  Egress IsolationMode `json:"egress,omitempty"`
+  // ExemptLabels, if set, causes the plugin to skip a namespace entirely once this
+  // rule is the one NamespaceSelector has matched, when that namespace carries every
+  // one of these labels.
+  ExemptLabels map[string]string `json:"exemptLabels,omitempty"`
+}
+
+// normalizedIngress returns rule's ingress mode, defaulting an unset mode to
+// DefaultDeny. Every caller that branches on rule.Ingress (the annotation value and
+// the materialized NetworkPolicy's policyTypes) must go through this so they agree
+// on what an empty mode means.
+func normalizedIngress(rule *Rule) IsolationMode {
+  if rule.Ingress == "" {
+    return DefaultDeny
+  }
+  return rule.Ingress
+}
+
+// Configuration is the configuration for the NamespaceNetworkIsolation admission plugin.
+type Configuration struct {
+  unversioned.TypeMeta
+
+  // Rules is the prioritized list of isolation rules evaluated against each namespace.
+  Rules []Rule `json:"rules"`
+
+  // AnnotationAllowList is a list of regexes matched against annotation keys. A
+  // user-supplied annotation matching one of these patterns is restored after the
+  // plugin applies its own default isolation annotation, letting operators override
+  // the computed posture on specific namespaces (e.g. `^net\.beta\.kubernetes\.io/.*`).
+  AnnotationAllowList []string `json:"annotationAllowList,omitempty"`
+
+  // AnnotationDenyList is evaluated after AnnotationAllowList and vetoes any
+  // overrides it would otherwise allow.
+  AnnotationDenyList []string `json:"annotationDenyList,omitempty"`
+}
+
+// LoadConfiguration parses a NamespaceNetworkIsolation Configuration from the given
+// YAML or JSON config reader. A nil reader yields the default configuration.
+func LoadConfiguration(config io.Reader) (*Configuration, error) {
+  if config == nil {
+    return defaultConfiguration(), nil
+  }
+
+  decoder := yaml.NewYAMLOrJSONDecoder(config, 4096)
+  config2 := &Configuration{}
+  if err := decoder.Decode(config2); err != nil {
+    return nil, fmt.Errorf("failed to decode NamespaceNetworkIsolation configuration: %v", err)
+  }
+  if len(config2.Rules) == 0 {
+    return defaultConfiguration(), nil
+  }
+  return config2, nil
+}
+
+// defaultConfiguration preserves the plugin's historical behavior: every namespace is
+// annotated DefaultDeny on ingress.
+func defaultConfiguration() *Configuration {
+  return &Configuration{
+    Rules: []Rule{
+      {Ingress: DefaultDeny},
+    },
+  }
+}
@@ -0,0 +1,54 @@
+package isolation
+
+import (
+  "k8s.io/kubernetes/pkg/api"
+  "k8s.io/kubernetes/pkg/api/unversioned"
+  "k8s.io/kubernetes/pkg/apis/networking"
+)
+
+// defaultDenyAllName is the name given to the NetworkPolicy materialized for a
+// namespace's default-deny isolation posture.
+const defaultDenyAllName = "default-deny-all"
+
+// buildDefaultDenyNetworkPolicy returns the NetworkPolicy that enforces the given
+// policy types for namespace ns. An empty podSelector selects every pod in the
+// namespace.
+func buildDefaultDenyNetworkPolicy(ns string, policyTypes []networking.PolicyType) *networking.NetworkPolicy {
+  return &networking.NetworkPolicy{
+    ObjectMeta: api.ObjectMeta{
+      Name:      defaultDenyAllName,
+      Namespace: ns,
+    },
+    Spec: networking.NetworkPolicySpec{
+      PodSelector: unversionedEverythingSelector(),
+      PolicyTypes: policyTypes,
+    },
+  }
+}
+
+func networkPolicyUpToDate(existing, desired *networking.NetworkPolicy) bool {
+  if len(existing.Spec.PolicyTypes) != len(desired.Spec.PolicyTypes) {
+    return false
+  }
+  for i := range existing.Spec.PolicyTypes {
+    if existing.Spec.PolicyTypes[i] != desired.Spec.PolicyTypes[i] {
+      return false
+    }
+  }
+  return true
+}
+
+func policyTypesForRule(rule *Rule) []networking.PolicyType {
+  var types []networking.PolicyType
+  if normalizedIngress(rule) == DefaultDeny {
+    types = append(types, networking.PolicyTypeIngress)
+  }
+  if rule.Egress == DefaultDeny {
+    types = append(types, networking.PolicyTypeEgress)
+  }
+  return types
+}
+
+func unversionedEverythingSelector() unversioned.LabelSelector {
+  return unversioned.LabelSelector{}
+}
@@ -0,0 +1,72 @@
+package isolation
+
+import (
+  "strings"
+  "testing"
+
+  "k8s.io/kubernetes/pkg/apis/networking"
+)
+
+// TestAnnotationAndPolicyTypesAgreeOnIngress guards against annotationValue and
+// policyTypesForRule disagreeing about what an empty rule.Ingress means: both must
+// treat it as DefaultDeny, or the materialized NetworkPolicy under-enforces relative
+// to the annotation it's supposed to mirror.
+func TestAnnotationAndPolicyTypesAgreeOnIngress(t *testing.T) {
+  tests := []struct {
+    name             string
+    rule             Rule
+    wantIngressTypes bool
+    wantEgressTypes  bool
+  }{
+    {
+      name:             "empty ingress, no egress defaults to ingress-only DefaultDeny",
+      rule:             Rule{},
+      wantIngressTypes: true,
+    },
+    {
+      name:             "empty ingress with egress DefaultDeny gets both policy types",
+      rule:             Rule{Egress: DefaultDeny},
+      wantIngressTypes: true,
+      wantEgressTypes:  true,
+    },
+    {
+      name:             "explicit ingress DefaultDeny",
+      rule:             Rule{Ingress: DefaultDeny},
+      wantIngressTypes: true,
+    },
+    {
+      name: "DefaultAllow ingress gets no ingress policy type",
+      rule: Rule{Ingress: DefaultAllow},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      value := annotationValue(&tt.rule)
+      annotationHasIngressDeny := strings.Contains(value, `"ingress": {"isolation": "DefaultDeny"}`)
+      if annotationHasIngressDeny != tt.wantIngressTypes {
+        t.Fatalf("annotationValue(%+v) = %q, ingress DefaultDeny present = %v, want %v", tt.rule, value, annotationHasIngressDeny, tt.wantIngressTypes)
+      }
+
+      types := policyTypesForRule(&tt.rule)
+      gotIngress := containsPolicyType(types, networking.PolicyTypeIngress)
+      gotEgress := containsPolicyType(types, networking.PolicyTypeEgress)
+
+      if gotIngress != tt.wantIngressTypes {
+        t.Fatalf("policyTypesForRule(%+v) ingress = %v, want %v (must match annotationValue)", tt.rule, gotIngress, tt.wantIngressTypes)
+      }
+      if gotEgress != tt.wantEgressTypes {
+        t.Fatalf("policyTypesForRule(%+v) egress = %v, want %v", tt.rule, gotEgress, tt.wantEgressTypes)
+      }
+    })
+  }
+}
+
+func containsPolicyType(types []networking.PolicyType, want networking.PolicyType) bool {
+  for _, pt := range types {
+    if pt == want {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,60 @@
+package isolation
+
+import (
+  "k8s.io/kubernetes/pkg/api"
+  "k8s.io/kubernetes/pkg/api/unversioned"
+  "k8s.io/kubernetes/pkg/labels"
+)
+
+// resolveRule walks the configured rules in order and returns the first one whose
+// NamespaceSelector matches namespaceObj's labels. If that matching rule declares
+// ExemptLabels and namespaceObj carries all of them, exempt is true and rule is nil,
+// signalling that the plugin should leave the namespace untouched. A rule that
+// doesn't match namespaceObj never takes effect, so its ExemptLabels can't exempt an
+// unrelated namespace out from under a later, actually-matching rule.
+func resolveRule(config *Configuration, namespaceObj *api.Namespace) (rule *Rule, exempt bool) {
+  if config == nil {
+    return nil, false
+  }
+
+  nsLabels := labels.Set(namespaceObj.GetLabels())
+
+  for i := range config.Rules {
+    r := &config.Rules[i]
+
+    selector, err := selectorForRule(r)
+    if err != nil {
+      // an invalid selector can never match; skip this rule rather than fail admission
+      continue
+    }
+    if !selector.Matches(nsLabels) {
+      continue
+    }
+
+    if isExempt(r.ExemptLabels, nsLabels) {
+      return nil, true
+    }
+    return r, false
+  }
+
+  return nil, false
+}
+
+func isExempt(exemptLabels map[string]string, nsLabels labels.Set) bool {
+  if len(exemptLabels) == 0 {
+    return false
+  }
+  for k, v := range exemptLabels {
+    if nsLabels.Get(k) != v {
+      return false
+    }
+  }
+  return true
+}
+
+func selectorForRule(r *Rule) (labels.Selector, error) {
+  if r.NamespaceSelector == nil {
+    return labels.Everything(), nil
+  }
+  return unversioned.LabelSelectorAsSelector(r.NamespaceSelector)
+}
@@ -0,0 +1,125 @@
+package isolation
+
+import (
+  "testing"
+
+  "k8s.io/kubernetes/pkg/api"
+  "k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+func namespaceWithLabels(name string, labels map[string]string) *api.Namespace {
+  return &api.Namespace{
+    ObjectMeta: api.ObjectMeta{
+      Name:   name,
+      Labels: labels,
+    },
+  }
+}
+
+func selector(matchLabels map[string]string) *unversioned.LabelSelector {
+  return &unversioned.LabelSelector{MatchLabels: matchLabels}
+}
+
+func TestResolveRule(t *testing.T) {
+  tests := []struct {
+    name       string
+    config     *Configuration
+    namespace  *api.Namespace
+    wantRule   *Rule
+    wantExempt bool
+  }{
+    {
+      name:   "nil config matches nothing",
+      config: nil,
+      namespace: namespaceWithLabels("default", nil),
+    },
+    {
+      name:      "no rules matches nothing",
+      config:    &Configuration{},
+      namespace: namespaceWithLabels("default", nil),
+    },
+    {
+      name: "nil selector matches every namespace",
+      config: &Configuration{
+        Rules: []Rule{{Ingress: DefaultDeny}},
+      },
+      namespace: namespaceWithLabels("default", nil),
+      wantRule:  &Rule{Ingress: DefaultDeny},
+    },
+    {
+      name: "first matching rule wins",
+      config: &Configuration{
+        Rules: []Rule{
+          {NamespaceSelector: selector(map[string]string{"tier": "tenant"}), Ingress: DefaultDeny, Egress: DefaultDeny},
+          {Ingress: DefaultDeny},
+        },
+      },
+      namespace: namespaceWithLabels("acme", map[string]string{"tier": "tenant"}),
+      wantRule:  &Rule{NamespaceSelector: selector(map[string]string{"tier": "tenant"}), Ingress: DefaultDeny, Egress: DefaultDeny},
+    },
+    {
+      name: "ExemptLabels on a rule that does not match this namespace must not exempt it",
+      config: &Configuration{
+        Rules: []Rule{
+          {
+            NamespaceSelector: selector(map[string]string{"tier": "tenant"}),
+            ExemptLabels:      map[string]string{"name": "kube-system"},
+            Ingress:           DefaultDeny,
+          },
+          {Ingress: DefaultDeny},
+        },
+      },
+      // carries rule 1's ExemptLabels but doesn't match rule 1's selector; it must
+      // fall through to rule 2 rather than being exempted by a rule that was never
+      // "about" it.
+      namespace: namespaceWithLabels("kube-system", map[string]string{"name": "kube-system"}),
+      wantRule:  &Rule{Ingress: DefaultDeny},
+    },
+    {
+      name: "ExemptLabels exempts a namespace only once its rule matches",
+      config: &Configuration{
+        Rules: []Rule{
+          {ExemptLabels: map[string]string{"name": "kube-system"}, Ingress: DefaultDeny},
+          {Ingress: DefaultDeny},
+        },
+      },
+      namespace:  namespaceWithLabels("kube-system", map[string]string{"name": "kube-system"}),
+      wantExempt: true,
+    },
+    {
+      name: "invalid selector is skipped rather than failing admission",
+      config: &Configuration{
+        Rules: []Rule{
+          {NamespaceSelector: &unversioned.LabelSelector{MatchLabels: map[string]string{"": "x"}, MatchExpressions: []unversioned.LabelSelectorRequirement{{Key: "", Operator: "bogus-operator"}}}, Ingress: DefaultAllow},
+          {Ingress: DefaultDeny},
+        },
+      },
+      namespace: namespaceWithLabels("acme", nil),
+      wantRule:  &Rule{Ingress: DefaultDeny},
+    },
+  }
+
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      rule, exempt := resolveRule(tt.config, tt.namespace)
+
+      if exempt != tt.wantExempt {
+        t.Fatalf("exempt = %v, want %v", exempt, tt.wantExempt)
+      }
+
+      if tt.wantRule == nil {
+        if rule != nil {
+          t.Fatalf("rule = %+v, want nil", rule)
+        }
+        return
+      }
+
+      if rule == nil {
+        t.Fatalf("rule = nil, want %+v", tt.wantRule)
+      }
+      if rule.Ingress != tt.wantRule.Ingress || rule.Egress != tt.wantRule.Egress {
+        t.Fatalf("rule = %+v, want %+v", rule, tt.wantRule)
+      }
+    })
+  }
+}
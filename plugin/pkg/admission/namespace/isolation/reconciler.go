@@ -0,0 +1,217 @@
+package isolation
+
+import (
+  "encoding/json"
+  "fmt"
+  "regexp"
+  "time"
+
+  clientset "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset"
+
+  "k8s.io/kubernetes/pkg/api"
+  k8sError "k8s.io/kubernetes/pkg/api/errors"
+  "k8s.io/kubernetes/pkg/client/cache"
+  "k8s.io/kubernetes/pkg/controller/framework"
+  "k8s.io/kubernetes/pkg/types"
+  utilruntime "k8s.io/kubernetes/pkg/util/runtime"
+  "k8s.io/kubernetes/pkg/util/retry"
+  "k8s.io/kubernetes/pkg/util/wait"
+  "k8s.io/kubernetes/pkg/util/workqueue"
+)
+
+// reconciler is the event-driven counterpart to Admit: Admit only ever mutates the
+// object the apiserver is already persisting, so it proves nothing about the
+// namespace's actual stored state. reconciler instead watches namespaceInformer,
+// and for every Add/Update re-derives what the namespace's annotation, labels and
+// NetworkPolicy should be, patching whatever has drifted or never landed.
+type reconciler struct {
+  client            clientset.Interface
+  namespaceInformer framework.SharedIndexInformer
+  config            *Configuration
+  allowList         []*regexp.Regexp
+  denyList          []*regexp.Regexp
+  queue             workqueue.RateLimitingInterface
+}
+
+// newReconciler wires a workqueue-backed reconciler off namespaceInformer. Call Run
+// to start processing; it does nothing until then.
+func newReconciler(client clientset.Interface, namespaceInformer framework.SharedIndexInformer, config *Configuration, allowList, denyList []*regexp.Regexp) *reconciler {
+  r := &reconciler{
+    client:            client,
+    namespaceInformer: namespaceInformer,
+    config:            config,
+    allowList:         allowList,
+    denyList:          denyList,
+    queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "namespace_network_isolation"),
+  }
+
+  namespaceInformer.AddEventHandler(framework.ResourceEventHandlerFuncs{
+    AddFunc:    r.enqueue,
+    UpdateFunc: func(oldObj, newObj interface{}) { r.enqueue(newObj) },
+  })
+
+  return r
+}
+
+func (r *reconciler) enqueue(obj interface{}) {
+  key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+  if err != nil {
+    utilruntime.HandleError(fmt.Errorf("couldn't get key for namespace %+v: %v", obj, err))
+    return
+  }
+  r.queue.Add(key)
+}
+
+// Run starts numWorkers goroutines draining the queue until stopCh is closed, then
+// shuts the queue down and returns.
+func (r *reconciler) Run(numWorkers int, stopCh <-chan struct{}) {
+  defer r.queue.ShutDown()
+  for i := 0; i < numWorkers; i++ {
+    go wait.Until(r.worker, time.Second, stopCh)
+  }
+  <-stopCh
+}
+
+func (r *reconciler) worker() {
+  for r.processNextItem() {
+  }
+}
+
+func (r *reconciler) processNextItem() bool {
+  key, quit := r.queue.Get()
+  if quit {
+    return false
+  }
+  defer r.queue.Done(key)
+
+  if err := r.syncNamespace(key.(string)); err != nil {
+    // AddRateLimited backs off exponentially, so a persistently failing namespace
+    // doesn't hammer the apiserver.
+    utilruntime.HandleError(fmt.Errorf("reconciling namespace isolation for %q: %v", key, err))
+    r.queue.AddRateLimited(key)
+    return true
+  }
+
+  r.queue.Forget(key)
+  return true
+}
+
+// syncNamespace verifies the namespace named by key carries its configured
+// isolation annotation, labels and NetworkPolicy, and re-applies anything missing.
+func (r *reconciler) syncNamespace(key string) error {
+  obj, exists, err := r.namespaceInformer.GetStore().GetByKey(key)
+  if err != nil {
+    return err
+  }
+  if !exists {
+    // namespace was deleted; nothing to reconcile
+    return nil
+  }
+
+  namespaceObj := obj.(*api.Namespace)
+  rule, exempt := resolveRule(r.config, namespaceObj)
+  if exempt || rule == nil {
+    return nil
+  }
+
+  if err := r.reconcileMetadata(namespaceObj, rule); err != nil {
+    return err
+  }
+  return r.reconcileNetworkPolicy(namespaceObj, rule)
+}
+
+// reconcileMetadata patches in the isolation annotation when it is missing or
+// drifted, skipping it if the operator has overridden it per the allow/deny list,
+// and the Name and kubernetes.io/metadata.name labels when they are missing or
+// empty. A label already set to some other value is left as it is.
+func (r *reconciler) reconcileMetadata(namespaceObj *api.Namespace, rule *Rule) error {
+  annotations := namespaceObj.GetAnnotations()
+  labels := namespaceObj.GetLabels()
+
+  patchAnnotations := map[string]string{}
+  value := annotationValue(rule)
+  existing, hasOwn := annotations[IngressAnnotationKey]
+  if existing != value && !(hasOwn && r.allowOverride(IngressAnnotationKey)) {
+    patchAnnotations[IngressAnnotationKey] = value
+  }
+
+  patchLabels := map[string]string{}
+  if labels[NameLabelKey] == "" {
+    patchLabels[NameLabelKey] = namespaceObj.Name
+  }
+  if labels[MetadataNameLabelKey] == "" {
+    patchLabels[MetadataNameLabelKey] = namespaceObj.Name
+  }
+
+  if len(patchAnnotations) == 0 && len(patchLabels) == 0 {
+    return nil
+  }
+
+  return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+    return r.patchMetadata(namespaceObj.Name, patchAnnotations, patchLabels)
+  })
+}
+
+func (r *reconciler) allowOverride(key string) bool {
+  return allowOverride(r.allowList, r.denyList, key)
+}
+
+type metadataPatch struct {
+  Metadata struct {
+    Annotations map[string]string `json:"annotations,omitempty"`
+    Labels      map[string]string `json:"labels,omitempty"`
+  } `json:"metadata"`
+}
+
+func (r *reconciler) patchMetadata(name string, annotations, labels map[string]string) error {
+  var patch metadataPatch
+  if len(annotations) > 0 {
+    patch.Metadata.Annotations = annotations
+  }
+  if len(labels) > 0 {
+    patch.Metadata.Labels = labels
+  }
+
+  body, err := json.Marshal(patch)
+  if err != nil {
+    return err
+  }
+
+  _, err = r.client.Core().Namespaces().Patch(name, types.MergePatchType, body)
+  return err
+}
+
+// reconcileNetworkPolicy ensures namespaceObj has a NetworkPolicy matching the
+// policy types its rule prescribes, creating, updating or deleting it as needed. A
+// rule that no longer prescribes any DefaultDeny policy type (e.g. it was changed to
+// DefaultAllow) must not leave a stale default-deny-all NetworkPolicy enforcing the
+// old posture.
+func (r *reconciler) reconcileNetworkPolicy(namespaceObj *api.Namespace, rule *Rule) error {
+  policyTypes := policyTypesForRule(rule)
+  if len(policyTypes) == 0 {
+    err := r.client.Networking().NetworkPolicies(namespaceObj.Name).Delete(defaultDenyAllName, nil)
+    if k8sError.IsNotFound(err) {
+      return nil
+    }
+    return err
+  }
+
+  desired := buildDefaultDenyNetworkPolicy(namespaceObj.Name, policyTypes)
+
+  existing, err := r.client.Networking().NetworkPolicies(namespaceObj.Name).Get(defaultDenyAllName)
+  if k8sError.IsNotFound(err) {
+    _, err = r.client.Networking().NetworkPolicies(namespaceObj.Name).Create(desired)
+    return err
+  }
+  if err != nil {
+    return err
+  }
+
+  if networkPolicyUpToDate(existing, desired) {
+    return nil
+  }
+
+  existing.Spec = desired.Spec
+  _, err = r.client.Networking().NetworkPolicies(namespaceObj.Name).Update(existing)
+  return err
+}